@@ -0,0 +1,155 @@
+package otelfiber
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/gofiber/contrib/otelfiber"
+)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+
+	publicEndpoint   bool
+	publicEndpointFn func(*fiber.Ctx) bool
+
+	capturedRequestHeaders  []string
+	capturedResponseHeaders []string
+
+	filters       []func(*fiber.Ctx) bool
+	ignoredRoutes map[string]struct{}
+
+	recovery bool
+}
+
+// Option specifies instrumentation configuration options.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (o optionFunc) apply(c *options) {
+	o(c)
+}
+
+// WithPropagators specifies propagators to use for extracting information
+// from the HTTP requests. If none are specified, global ones will be used.
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return optionFunc(func(cfg *options) {
+		if propagators != nil {
+			cfg.propagators = propagators
+		}
+	})
+}
+
+// WithTracerProvider specifies a tracer provider to use for creating a
+// tracer. If none is specified, the global provider is used.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return optionFunc(func(cfg *options) {
+		if provider != nil {
+			cfg.tracerProvider = provider
+		}
+	})
+}
+
+// WithMeterProvider specifies a meter provider to use for creating a meter.
+// If none is specified, the global provider is used.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return optionFunc(func(cfg *options) {
+		if provider != nil {
+			cfg.meterProvider = provider
+		}
+	})
+}
+
+// WithPublicEndpoint configures the Middleware to link the span with an
+// incoming span context instead of making it a child of that span. This is
+// intended for servers that are the "front door" of a system and receive
+// requests from untrusted clients, where the upstream trace should be
+// recorded for correlation but must not become part of the service's own
+// trace hierarchy.
+func WithPublicEndpoint() Option {
+	return optionFunc(func(cfg *options) {
+		cfg.publicEndpoint = true
+	})
+}
+
+// WithPublicEndpointFn runs with every request, and allows conditionally
+// configuring the Middleware to link the span with an incoming span context
+// instead of making it a child of that span. This is useful when a single
+// service serves both public and internal endpoints and the decision must
+// be made per request.
+func WithPublicEndpointFn(fn func(*fiber.Ctx) bool) Option {
+	return optionFunc(func(cfg *options) {
+		cfg.publicEndpointFn = fn
+	})
+}
+
+// WithCapturedRequestHeaders configures the Middleware to record the given
+// request header names as span attributes, named
+// `http.request.header.<lowercased-name>`. Header name matching is
+// case-insensitive; headers that are not present on a given request are
+// skipped.
+func WithCapturedRequestHeaders(headers []string) Option {
+	return optionFunc(func(cfg *options) {
+		cfg.capturedRequestHeaders = headers
+	})
+}
+
+// WithCapturedResponseHeaders configures the Middleware to record the given
+// response header names as span attributes, named
+// `http.response.header.<lowercased-name>`. Header name matching is
+// case-insensitive; headers that are not present on a given response are
+// skipped.
+func WithCapturedResponseHeaders(headers []string) Option {
+	return optionFunc(func(cfg *options) {
+		cfg.capturedResponseHeaders = headers
+	})
+}
+
+// WithFilter adds a filter to the list of filters used by the Middleware.
+// Filters are called before tracing/metrics are recorded for a given
+// request; if any registered filter returns false, the request is passed
+// straight to the next handler without instrumentation. WithFilter may be
+// used more than once, and filters are evaluated in the order they were
+// registered.
+func WithFilter(filter func(*fiber.Ctx) bool) Option {
+	return optionFunc(func(cfg *options) {
+		cfg.filters = append(cfg.filters, filter)
+	})
+}
+
+// WithIgnoredRoutes excludes requests whose path (as reported by
+// `(*fiber.Ctx).Path()`) equals one of the given paths from tracing and
+// metrics, e.g. health checks or metrics scrape endpoints. The comparison is
+// exact: a route pattern with parameters, such as "/user/:id", does not
+// match the requests it routes.
+func WithIgnoredRoutes(routes ...string) Option {
+	return optionFunc(func(cfg *options) {
+		if cfg.ignoredRoutes == nil {
+			cfg.ignoredRoutes = make(map[string]struct{}, len(routes))
+		}
+		for _, route := range routes {
+			cfg.ignoredRoutes[route] = struct{}{}
+		}
+	})
+}
+
+// WithRecovery wraps the downstream handler chain in a recover() so that a
+// panic is recorded on the span as an exception, with a stack trace, and
+// reported as a 500 in the request.duration metric, before being re-panicked
+// so Fiber's own recover/error-handling middleware still runs. It is off by
+// default to preserve prior behavior; apps that don't already run a
+// recover middleware upstream of this one should enable it.
+func WithRecovery(recovery bool) Option {
+	return optionFunc(func(cfg *options) {
+		cfg.recovery = recovery
+	})
+}
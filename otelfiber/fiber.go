@@ -0,0 +1,212 @@
+package otelfiber
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Middleware returns a fiber handler that traces incoming requests and
+// reports the resulting spans and metrics through the configured
+// TracerProvider / MeterProvider (the global ones are used by default).
+func Middleware(service string, opts ...Option) fiber.Handler {
+	cfg := options{}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	if cfg.tracerProvider == nil {
+		cfg.tracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.meterProvider == nil {
+		cfg.meterProvider = otel.GetMeterProvider()
+	}
+	if cfg.propagators == nil {
+		cfg.propagators = otel.GetTextMapPropagator()
+	}
+
+	tracer := cfg.tracerProvider.Tracer(instrumentationName)
+	meter := cfg.meterProvider.Meter(instrumentationName)
+
+	requestDuration, err := meter.Float64Histogram(
+		"http.server.request.duration",
+		metric.WithUnit("ms"),
+		metric.WithDescription("measures the duration of inbound HTTP requests"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(c *fiber.Ctx) error {
+		if _, ignored := cfg.ignoredRoutes[c.Path()]; ignored {
+			return c.Next()
+		}
+		for _, filter := range cfg.filters {
+			if !filter(c) {
+				return c.Next()
+			}
+		}
+
+		headers := propagation.HeaderCarrier{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			headers.Set(string(key), string(value))
+		})
+		ctx := cfg.propagators.Extract(c.UserContext(), headers)
+
+		isPublicEndpoint := cfg.publicEndpoint || (cfg.publicEndpointFn != nil && cfg.publicEndpointFn(c))
+
+		startAttrs := httpServerAttributesFromContext(c, service)
+		startAttrs = append(startAttrs, capturedHeaderAttributes("http.request.header.", cfg.capturedRequestHeaders, c.Request().Header.PeekAll)...)
+
+		startOpts := []oteltrace.SpanStartOption{
+			oteltrace.WithAttributes(startAttrs...),
+			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+		}
+
+		if isPublicEndpoint {
+			psc := oteltrace.SpanContextFromContext(ctx)
+			startOpts = append(startOpts, oteltrace.WithNewRoot())
+			if psc.IsValid() {
+				startOpts = append(startOpts, oteltrace.WithLinks(oteltrace.Link{SpanContext: psc}))
+			}
+		}
+
+		spanName := c.Path()
+		ctx, span := tracer.Start(ctx, spanName, startOpts...)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+
+		start := time.Now()
+
+		var err error
+		func() {
+			if cfg.recovery {
+				defer func() {
+					if r := recover(); r != nil {
+						span.RecordError(fmt.Errorf("%v", r), oteltrace.WithStackTrace(true))
+						span.SetStatus(codes.Error, "panic")
+						attrs := append(httpServerAttributesFromContext(c, service), semconv.HTTPStatusCodeKey.Int(http.StatusInternalServerError))
+						requestDuration.Record(ctx, float64(time.Since(start).Milliseconds()), metric.WithAttributes(attrs...))
+						panic(r)
+					}
+				}()
+			}
+			err = c.Next()
+		}()
+
+		// Only after c.Next() does c.Route() report the matched handler's
+		// route; before that it is this middleware's own route.
+		route := c.Route().Path
+		span.SetName(route)
+		span.SetAttributes(semconv.HTTPRouteKey.String(route))
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// Fiber's ErrorHandler (default or app-supplied) hasn't run yet
+			// at this point — it runs later, back in app.handler(), once our
+			// own c.Next() call above returns. For a *fiber.Error we know the
+			// intended status up front; for a plain error we don't, so we
+			// fall back to the default ErrorHandler's mapping (500). This is
+			// a deliberate, accepted tradeoff: an app with a custom
+			// ErrorHandler that maps plain errors to something other than
+			// 500 will see the span/metric status diverge from the real
+			// response (see TestErrorWithCustomErrorHandler).
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			} else {
+				status = http.StatusInternalServerError
+			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			spanStatus, spanMessage := semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, oteltrace.SpanKindServer)
+			span.SetStatus(spanStatus, spanMessage)
+		}
+
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		span.SetAttributes(capturedHeaderAttributes("http.response.header.", cfg.capturedResponseHeaders, c.Response().Header.PeekAll)...)
+
+		attrs := append(httpServerAttributesFromContext(c, service), semconv.HTTPStatusCodeKey.Int(status))
+		requestDuration.Record(ctx, float64(time.Since(start).Milliseconds()), metric.WithAttributes(attrs...))
+
+		return err
+	}
+}
+
+func httpServerAttributesFromContext(c *fiber.Ctx, service string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.HTTPServerNameKey.String(service),
+		semconv.HTTPMethodKey.String(c.Method()),
+		semconv.HTTPTargetKey.String(c.OriginalURL()),
+		semconv.HTTPRouteKey.String(c.Route().Path),
+		semconv.NetHostNameKey.String(c.Hostname()),
+	}
+}
+
+// capturedHeaderAttributes builds span attributes for the given header
+// names, one `attribute.StringSlice` per header that is actually present.
+// Matching is case-insensitive and missing headers are skipped rather than
+// recording an empty attribute.
+func capturedHeaderAttributes(prefix string, headers []string, peek func(key string) [][]byte) []attribute.KeyValue {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(headers))
+	for _, name := range headers {
+		values := peek(name)
+		if len(values) == 0 {
+			continue
+		}
+
+		strs := make([]string, len(values))
+		for i, v := range values {
+			strs[i] = string(v)
+		}
+
+		key := attribute.Key(prefix + strings.ToLower(name))
+		attrs = append(attrs, key.StringSlice(strs))
+	}
+
+	return attrs
+}
+
+// hasBasicAuth decodes an HTTP Basic "Authorization" header value and
+// returns the embedded username. The second return value reports whether
+// auth was a well-formed Basic credential.
+func hasBasicAuth(auth string) (string, bool) {
+	if auth == "" {
+		return "", false
+	}
+
+	const prefix = "Basic "
+	if !strings.HasPrefix(auth, prefix) {
+		return "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", false
+	}
+
+	user, _, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", false
+	}
+
+	return user, true
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	fiberrecover "github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	b3prop "go.opentelemetry.io/contrib/propagators/b3"
@@ -99,8 +100,8 @@ func TestError(t *testing.T) {
 	// setup
 	app := fiber.New()
 	app.Use(Middleware("foobar", WithTracerProvider(provider)))
-	// configure a handler that returns an error and 5xx status
-	// code
+	// configure a handler that returns a plain (non-*fiber.Error) error;
+	// Fiber's default ErrorHandler maps this to a 500 status code
 	app.Get("/server_err", func(ctx *fiber.Ctx) error {
 		return errors.New("oh no")
 	})
@@ -119,6 +120,39 @@ func TestError(t *testing.T) {
 	assert.Equal(t, codes.Error, span.StatusCode())
 }
 
+func TestSpanStatus(t *testing.T) {
+	testCases := []struct {
+		status int
+		code   codes.Code
+	}{
+		{http.StatusOK, codes.Unset},
+		{http.StatusBadRequest, codes.Unset},
+		{http.StatusUnauthorized, codes.Unset},
+		{http.StatusNotFound, codes.Unset},
+		{http.StatusInternalServerError, codes.Error},
+		{http.StatusServiceUnavailable, codes.Error},
+	}
+
+	for _, tC := range testCases {
+		t.Run(http.StatusText(tC.status), func(t *testing.T) {
+			sr := new(oteltest.SpanRecorder)
+			provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+			app := fiber.New()
+			app.Use(Middleware("foobar", WithTracerProvider(provider)))
+			app.Get("/status", func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(tC.status)
+			})
+
+			_, _ = app.Test(httptest.NewRequest("GET", "/status", nil))
+
+			spans := sr.Completed()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tC.code, spans[0].StatusCode())
+		})
+	}
+}
+
 func TestErrorOnlyHandledOnce(t *testing.T) {
 	timesHandlingError := 0
 	app := fiber.New(fiber.Config{
@@ -136,6 +170,37 @@ func TestErrorOnlyHandledOnce(t *testing.T) {
 	assert.Equal(t, 1, timesHandlingError)
 }
 
+// TestErrorWithCustomErrorHandler documents a known, accepted limitation:
+// the middleware runs before Fiber's ErrorHandler has had a chance to map a
+// plain (non-*fiber.Error) error to a status code, so it records the
+// default ErrorHandler's mapping (500) on the span/metric even when a
+// custom ErrorHandler maps the error to something else. The client-visible
+// response reflects the custom mapping; the telemetry does not.
+func TestErrorWithCustomErrorHandler(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(ctx *fiber.Ctx, err error) error {
+			return ctx.Status(http.StatusTeapot).SendString(err.Error())
+		},
+	})
+	app.Use(Middleware("foobar", WithTracerProvider(provider)))
+	app.Get("/server_err", func(ctx *fiber.Ctx) error {
+		return errors.New("oh no")
+	})
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/server_err", nil))
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	// the middleware ran before the custom ErrorHandler, so it could only
+	// fall back to the default mapping rather than the real 418 response.
+	assert.Equal(t, attribute.IntValue(http.StatusInternalServerError), span.Attributes()["http.status_code"])
+}
+
 func TestGetSpanNotInstrumented(t *testing.T) {
 	var gotSpan oteltrace.Span
 
@@ -243,6 +308,207 @@ func TestHasBasicAuth(t *testing.T) {
 	}
 }
 
+func TestWithPublicEndpoint(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+	remoteSpan := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x01},
+		SpanID:     oteltrace.SpanID{0x01},
+		TraceFlags: oteltrace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := oteltrace.ContextWithRemoteSpanContext(context.Background(), remoteSpan)
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+	b3 := b3prop.New()
+	b3.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	app := fiber.New()
+	app.Use(Middleware("foobar", WithTracerProvider(provider), WithPropagators(b3), WithPublicEndpoint()))
+	app.Get("/user/:id", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusNoContent)
+	})
+
+	_, _ = app.Test(r)
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.True(t, span.SpanContext().IsValid())
+	assert.Equal(t, oteltrace.SpanID{}, span.ParentSpanID())
+
+	require.Len(t, span.Links(), 1)
+	assert.Equal(t, remoteSpan.TraceID(), span.Links()[0].SpanContext.TraceID())
+	assert.Equal(t, remoteSpan.SpanID(), span.Links()[0].SpanContext.SpanID())
+}
+
+func TestWithPublicEndpointFn(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+	remoteSpan := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x01},
+		SpanID:     oteltrace.SpanID{0x01},
+		TraceFlags: oteltrace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := oteltrace.ContextWithRemoteSpanContext(context.Background(), remoteSpan)
+
+	isPublic := false
+	fn := func(*fiber.Ctx) bool { return isPublic }
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	b3 := b3prop.New()
+	b3.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	app := fiber.New()
+	app.Use(Middleware("foobar", WithTracerProvider(provider), WithPropagators(b3), WithPublicEndpointFn(fn)))
+	app.Get("/user/:id", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusNoContent)
+	})
+
+	_, _ = app.Test(r)
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.NotEqual(t, oteltrace.SpanID{}, span.ParentSpanID())
+	assert.Len(t, span.Links(), 0)
+
+	isPublic = true
+	_, _ = app.Test(r)
+
+	spans = sr.Completed()
+	require.Len(t, spans, 2)
+	span = spans[1]
+	assert.Equal(t, oteltrace.SpanID{}, span.ParentSpanID())
+	require.Len(t, span.Links(), 1)
+	assert.Equal(t, remoteSpan.SpanID(), span.Links()[0].SpanContext.SpanID())
+}
+
+func TestWithCapturedRequestHeaders(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+	app := fiber.New()
+	app.Use(Middleware("foobar", WithTracerProvider(provider), WithCapturedRequestHeaders([]string{"X-Tenant", "X-Missing"})))
+	app.Get("/user/:id", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusNoContent)
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Add("X-Tenant", "acme")
+	r.Header.Add("X-Tenant", "globex")
+	_, _ = app.Test(r)
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.Equal(t, attribute.StringSliceValue([]string{"acme", "globex"}), span.Attributes()[attribute.Key("http.request.header.x-tenant")])
+	_, ok := span.Attributes()[attribute.Key("http.request.header.x-missing")]
+	assert.False(t, ok)
+}
+
+func TestWithCapturedResponseHeaders(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+	app := fiber.New()
+	app.Use(Middleware("foobar", WithTracerProvider(provider), WithCapturedResponseHeaders([]string{"X-Request-Id"})))
+	app.Get("/user/:id", func(ctx *fiber.Ctx) error {
+		ctx.Set("X-Request-Id", "req-1")
+		return ctx.SendStatus(http.StatusNoContent)
+	})
+
+	_, _ = app.Test(httptest.NewRequest("GET", "/user/123", nil))
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.Equal(t, attribute.StringSliceValue([]string{"req-1"}), span.Attributes()[attribute.Key("http.response.header.x-request-id")])
+}
+
+func TestWithIgnoredRoutes(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+	app := fiber.New()
+	app.Use(Middleware("foobar", WithTracerProvider(provider), WithIgnoredRoutes("/healthz", "/metrics")))
+	app.Get("/healthz", func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+	app.Get("/metrics", func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+	app.Get("/user/:id", func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+
+	_, _ = app.Test(httptest.NewRequest("GET", "/healthz", nil))
+	_, _ = app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	_, _ = app.Test(httptest.NewRequest("GET", "/user/123", nil))
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "/user/:id", spans[0].Name())
+}
+
+func TestWithFilter(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+
+	skipGet := func(c *fiber.Ctx) bool { return c.Method() != http.MethodGet }
+
+	app := fiber.New()
+	app.Use(Middleware("foobar", WithTracerProvider(provider), WithFilter(skipGet)))
+	app.Get("/user/:id", func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+	app.Post("/user/:id", func(ctx *fiber.Ctx) error { return ctx.SendStatus(http.StatusOK) })
+
+	_, _ = app.Test(httptest.NewRequest("GET", "/user/123", nil))
+	_, _ = app.Test(httptest.NewRequest("POST", "/user/123", nil))
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	assert.Equal(t, attribute.StringValue("POST"), spans[0].Attributes()["http.method"])
+}
+
+func TestWithRecovery(t *testing.T) {
+	sr := new(oteltest.SpanRecorder)
+	provider := oteltest.NewTracerProvider(oteltest.WithSpanRecorder(sr))
+	reader := metric.NewManualReader()
+	meterProvider := metric.NewMeterProvider(metric.WithReader(reader))
+
+	// fiberrecover.New() sits above the otelfiber middleware so it can turn
+	// the re-panic triggered by WithRecovery back into a 500 response,
+	// mirroring how an app would actually be wired. Asserting via the HTTP
+	// response (rather than assert.Panics around app.Test) is required
+	// because app.Test runs the handler chain on a goroutine fasthttp
+	// spawns internally, not on the test's own goroutine, so recover() in
+	// this goroutine would never observe the panic.
+	app := fiber.New()
+	app.Use(fiberrecover.New())
+	app.Use(Middleware("foobar", WithTracerProvider(provider), WithMeterProvider(meterProvider), WithRecovery(true)))
+	app.Get("/panic", func(ctx *fiber.Ctx) error {
+		panic("boom")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/panic", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	spans := sr.Completed()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Len(t, span.Events(), 1)
+	_, ok := span.Events()[0].Attributes[semconv.ExceptionStacktraceKey]
+	assert.True(t, ok)
+	assert.Equal(t, codes.Error, span.StatusCode())
+
+	collected, err := reader.Collect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, collected.ScopeMetrics, 1)
+	require.Len(t, collected.ScopeMetrics[0].Metrics, 1)
+}
+
 func TestMetric(t *testing.T) {
 	reader := metric.NewManualReader()
 	provider := metric.NewMeterProvider(metric.WithReader(reader))